@@ -0,0 +1,144 @@
+package grpcp
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/fujiwara/grpcp/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient starts the real FileTransferService over an in-memory
+// bufconn listener and returns a Client wired directly to it, bypassing
+// Client.connect's dialing so archive round trips can be tested without a
+// real network listener.
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() { lis.Close() })
+	s := grpc.NewServer()
+	pb.RegisterFileTransferServiceServer(s, &server{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &Client{
+		opt:      &ClientOption{},
+		pb:       pb.NewFileTransferServiceClient(conn),
+		progress: NewProgressReporter(true),
+	}
+}
+
+// buildFixtureTree creates a directory, a nested subdirectory, a regular
+// file, an empty file, and a symlink to the regular file under root.
+func buildFixtureTree(t *testing.T, root string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "file.txt"), []byte("hello world"), 0644); err != nil {
+		t.Fatalf("write file.txt: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "empty.txt"), nil, 0644); err != nil {
+		t.Fatalf("write empty.txt: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("dir", "file.txt"), filepath.Join(root, "link")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+}
+
+// assertFixtureTree checks that root reconstructs the tree buildFixtureTree
+// created: the nested directory, both files (including the empty one, which
+// never gets a content chunk), and the symlink.
+func assertFixtureTree(t *testing.T, root string) {
+	t.Helper()
+	if st, err := os.Stat(filepath.Join(root, "dir")); err != nil || !st.IsDir() {
+		t.Fatalf("dir missing or not a directory: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "dir", "file.txt"))
+	if err != nil {
+		t.Fatalf("read file.txt: %v", err)
+	}
+	if string(content) != "hello world" {
+		t.Errorf("file.txt content = %q, want %q", content, "hello world")
+	}
+	st, err := os.Stat(filepath.Join(root, "dir", "empty.txt"))
+	if err != nil {
+		t.Fatalf("empty.txt missing: %v", err)
+	}
+	if st.Size() != 0 {
+		t.Errorf("empty.txt size = %d, want 0", st.Size())
+	}
+	target, err := os.Readlink(filepath.Join(root, "link"))
+	if err != nil {
+		t.Fatalf("readlink: %v", err)
+	}
+	if target != filepath.Join("dir", "file.txt") {
+		t.Errorf("link target = %q, want %q", target, filepath.Join("dir", "file.txt"))
+	}
+}
+
+func TestArchiveUploadDownloadRoundTrip(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	srcRoot := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(srcRoot, 0755); err != nil {
+		t.Fatalf("mkdir srcRoot: %v", err)
+	}
+	buildFixtureTree(t, srcRoot)
+
+	uploadedRoot := filepath.Join(t.TempDir(), "uploaded")
+	if err := c.uploadArchive(ctx, srcRoot, uploadedRoot); err != nil {
+		t.Fatalf("uploadArchive: %v", err)
+	}
+	assertFixtureTree(t, uploadedRoot)
+
+	pulledRoot := filepath.Join(t.TempDir(), "pulled")
+	if err := c.downloadArchive(ctx, uploadedRoot, pulledRoot); err != nil {
+		t.Fatalf("downloadArchive: %v", err)
+	}
+	assertFixtureTree(t, pulledRoot)
+}
+
+func TestSafeArchivePath(t *testing.T) {
+	root := t.TempDir()
+	cases := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "simple nested file", entry: "a/b.txt"},
+		{name: "parent traversal", entry: "../escape.txt", wantErr: true},
+		{name: "nested parent traversal", entry: "a/../../escape.txt", wantErr: true},
+		{name: "absolute path is joined under root, not escaping", entry: "/etc/passwd"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := safeArchivePath(root, tc.entry)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("safeArchivePath(%q, %q) = %q, want error", root, tc.entry, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("safeArchivePath(%q, %q): %v", root, tc.entry, err)
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil || rel == ".." || filepath.IsAbs(rel) {
+				t.Errorf("safeArchivePath(%q, %q) = %q, escapes root", root, tc.entry, path)
+			}
+		})
+	}
+}
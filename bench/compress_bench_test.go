@@ -0,0 +1,72 @@
+// Package bench benchmarks the codecs grpcp can negotiate per-transfer, so
+// a throughput-vs-CPU trade-off can be read straight from `go test -bench`
+// output when picking a --compress value for a given workload.
+package bench
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math/rand"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// sample files representative of what grpcp typically moves: compressible
+// source text, and incompressible data such as already-compressed media.
+var samples = map[string][]byte{
+	"text":   bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 20000),
+	"random": randomBytes(1 << 20),
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(b)
+	return b
+}
+
+func BenchmarkGzip(b *testing.B) {
+	for name, data := range samples {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				if _, err := w.Write(data); err != nil {
+					b.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkZstd(b *testing.B) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer enc.Close()
+	for name, data := range samples {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				enc.EncodeAll(data, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkS2(b *testing.B) {
+	for name, data := range samples {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				s2.Encode(nil, data)
+			}
+		})
+	}
+}
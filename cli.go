@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/alecthomas/kong"
 )
@@ -11,18 +12,58 @@ import (
 var LogLevel = new(slog.LevelVar)
 
 type CLI struct {
-	Host string `name:"host" short:"h" default:"localhost" help:"host name"`
-	Port int    `name:"port" short:"p" default:"8022" help:"port number"`
+	Host      string `name:"host" short:"h" help:"host name (default localhost for tcp), socket path, or tunnel command, depending on --transport; also accepts a URL-style target such as unix:///path or stdio://<command>"`
+	Port      int    `name:"port" short:"p" default:"8022" help:"port number (tcp transport only)"`
+	Transport string `name:"transport" default:"tcp" enum:"tcp,unix,stdio" help:"transport to use: tcp, unix, or stdio"`
 
 	Server bool `name:"server" short:"s" help:"run as server"`
 	Quiet  bool `name:"quiet" short:"q" help:"quiet mode for client"`
 	Debug  bool `name:"debug" short:"d" help:"enable debug log for client and server"`
 	Kill   bool `name:"kill" short:"k" help:"kill server"`
+	Pull   bool `name:"pull" help:"fetch src from the server into local dest, instead of uploading local src to the server"`
+
+	VerifyWith string `name:"verify-with" help:"verify src's signature against this pinned Ed25519 public key file instead of copying"`
+
+	TLS               bool   `name:"tls" help:"enable TLS"`
+	CertFile          string `name:"cert-file" help:"TLS certificate file"`
+	KeyFile           string `name:"key-file" help:"TLS key file"`
+	ServerName        string `name:"server-name" help:"expected server name for TLS verification"`
+	CAFile            string `name:"ca-file" help:"CA certificate file to verify the peer against"`
+	ClientCAFile      string `name:"client-ca-file" help:"CA certificate file to verify client certificates against (server)"`
+	RequireClientCert bool   `name:"require-client-cert" help:"require a verified client certificate (server)"`
+	Trust             bool   `name:"trust" help:"trust a changed self-signed certificate fingerprint"`
+	SigningKeyFile    string `name:"signing-key-file" help:"Ed25519 private key used to sign Verify responses (server)"`
+
+	Compress string `name:"compress" help:"compress transferred content with this codec: none, gzip, zstd, s2" default:"none"`
 
 	Src  string `arg:"" optional:"" name:"src" short:"s" description:"source file path"`
 	Dest string `arg:"" optional:"" name:"dest" short:"d" description:"destination file path"`
 }
 
+// resolveTarget builds the Target a client dials or a server listens on.
+// A URL-style host (e.g. "unix:///path" or "stdio://<command>") is parsed
+// as-is and takes priority; otherwise host/port are combined according to
+// transport. Unlike tcp, the unix and stdio transports have no sensible
+// default target, so an empty host is rejected rather than silently
+// treated as a literal socket path or command named "localhost".
+func resolveTarget(transport, host string, port int) (*Target, error) {
+	if strings.Contains(host, "://") {
+		return ParseTarget(host)
+	}
+	switch transport {
+	case "unix", "stdio":
+		if host == "" {
+			return nil, fmt.Errorf("--transport %s requires --host to name a socket path or command", transport)
+		}
+		return &Target{Transport: transport, Addr: host}, nil
+	default:
+		if host == "" {
+			host = "localhost"
+		}
+		return &Target{Transport: "tcp", Addr: fmt.Sprintf("%s:%d", host, port)}, nil
+	}
+}
+
 func RunCLI(ctx context.Context) error {
 	var cli CLI
 	kong.Parse(&cli)
@@ -35,27 +76,73 @@ func RunCLI(ctx context.Context) error {
 		slog.SetLogLoggerLevel(slog.LevelInfo)
 	}
 
+	target, err := resolveTarget(cli.Transport, cli.Host, cli.Port)
+	if cli.Server && cli.Transport == "stdio" && cli.Host == "" {
+		// A stdio server speaks over its own stdin/stdout and needs no address.
+		target, err = &Target{Transport: "stdio"}, nil
+	}
+	if err != nil {
+		return err
+	}
+
 	if cli.Server {
 		opt := &ServerOption{
-			Port:   cli.Port,
-			Listen: cli.Host,
+			Target:            target,
+			TLS:               cli.TLS,
+			CertFile:          cli.CertFile,
+			KeyFile:           cli.KeyFile,
+			ClientCAFile:      cli.ClientCAFile,
+			RequireClientCert: cli.RequireClientCert,
+			SigningKeyFile:    cli.SigningKeyFile,
 		}
 		return RunServer(ctx, opt)
 	} else if cli.Kill {
 		opt := &ClientOption{
-			Host:  cli.Host,
-			Port:  cli.Port,
-			Quiet: cli.Quiet,
+			Target:     target,
+			Quiet:      cli.Quiet,
+			TLS:        cli.TLS,
+			CertFile:   cli.CertFile,
+			KeyFile:    cli.KeyFile,
+			ServerName: cli.ServerName,
+			CAFile:     cli.CAFile,
+			Trust:      cli.Trust,
 		}
 		client := NewClient(opt)
 		return client.Shutdown(ctx)
+	} else if cli.VerifyWith != "" {
+		if cli.Src == "" {
+			return fmt.Errorf("--verify-with requires src")
+		}
+		opt := &ClientOption{
+			Target:     target,
+			Quiet:      cli.Quiet,
+			TLS:        cli.TLS,
+			CertFile:   cli.CertFile,
+			KeyFile:    cli.KeyFile,
+			ServerName: cli.ServerName,
+			CAFile:     cli.CAFile,
+			Trust:      cli.Trust,
+		}
+		client := NewClient(opt)
+		return client.Verify(ctx, cli.Src, cli.VerifyWith)
 	} else if cli.Src != "" && cli.Dest != "" {
+		compression, err := parseCompression(cli.Compress)
+		if err != nil {
+			return err
+		}
 		opt := &ClientOption{
-			Port:  cli.Port,
-			Quiet: cli.Quiet,
+			Target:      target,
+			Quiet:       cli.Quiet,
+			TLS:         cli.TLS,
+			CertFile:    cli.CertFile,
+			KeyFile:     cli.KeyFile,
+			ServerName:  cli.ServerName,
+			CAFile:      cli.CAFile,
+			Trust:       cli.Trust,
+			Compression: compression,
 		}
 		client := NewClient(opt)
-		return client.Copy(ctx, cli.Src, cli.Dest)
+		return client.Copy(ctx, cli.Src, cli.Dest, cli.Pull)
 	} else {
 		return fmt.Errorf("expected: grpcp <src> <dest> or grpcp --server")
 	}
@@ -0,0 +1,692 @@
+package grpcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+
+	pb "github.com/fujiwara/grpcp/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const probeWindowSize = 4 * 1024 * 1024
+
+type ClientOption struct {
+	Target *Target
+	Quiet  bool
+
+	TLS        bool
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	CAFile     string
+	// Trust accepts a server certificate fingerprint that differs from the
+	// one already pinned in known_hosts, mirroring ssh's StrictHostKeyChecking.
+	Trust bool
+
+	// Compression is the codec applied to each content chunk of an
+	// Upload/UploadArchive stream.
+	Compression pb.Compression
+}
+
+type Client struct {
+	opt      *ClientOption
+	conn     *grpc.ClientConn
+	pb       pb.FileTransferServiceClient
+	progress ProgressReporter
+}
+
+func NewClient(opt *ClientOption) *Client {
+	return &Client{opt: opt, progress: NewProgressReporter(opt.Quiet)}
+}
+
+func (c *Client) connect(ctx context.Context) (pb.FileTransferServiceClient, error) {
+	if c.pb != nil {
+		return c.pb, nil
+	}
+	target := c.opt.Target
+	creds := insecure.NewCredentials()
+	if c.opt.TLS {
+		var err error
+		creds, err = c.tlsCredentials(target.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return dialTarget(ctx, target)
+	}
+	conn, err := grpc.NewClient("passthrough:///"+target.String(), grpc.WithTransportCredentials(creds), grpc.WithContextDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", target.String(), err)
+	}
+	c.conn = conn
+	c.pb = pb.NewFileTransferServiceClient(conn)
+	return c.pb, nil
+}
+
+// tlsCredentials builds transport credentials for host. With a CAFile it
+// verifies the server certificate against that CA as usual; without one it
+// assumes the self-signed certificate newListener generates and falls back
+// to TOFU fingerprint pinning instead.
+func (c *Client) tlsCredentials(host string) (credentials.TransportCredentials, error) {
+	if c.opt.CAFile == "" {
+		return credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true,
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				return verifyFingerprint(host, cs.PeerCertificates, c.opt.Trust)
+			},
+		}), nil
+	}
+	tlsConfig := &tls.Config{ServerName: c.opt.ServerName}
+	pool, err := loadCAPool(c.opt.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = pool
+	if c.opt.CertFile != "" && c.opt.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.opt.CertFile, c.opt.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// Verify fetches a signature over path's SHA-256 from the server and checks
+// it against the Ed25519 public key in pinnedPublicKeyFile, so authenticity
+// does not depend on whatever TLS trust was used to transfer the file.
+func (c *Client) Verify(ctx context.Context, path, pinnedPublicKeyFile string) error {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := cl.Verify(ctx, &pb.VerifyRequest{Filename: path})
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	pub, err := loadEd25519PublicKey(pinnedPublicKeyFile)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, resp.Checksum, resp.Signature) {
+		return fmt.Errorf("signature verification failed for %s", path)
+	}
+	slog.Info("verified file signature", "path", path)
+	return nil
+}
+
+func loadEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+func (c *Client) Shutdown(ctx context.Context) error {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := cl.Shutdown(ctx, &pb.ShutdownRequest{}); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+	return nil
+}
+
+// Copy copies src to dest. By default src is local and dest is on the
+// server (upload); with pull set, src is on the server and dest is local
+// (download). Either direction detects a directory and streams it as an
+// archive so the whole tree is reconstructed on the other side; otherwise
+// a single file is transferred.
+func (c *Client) Copy(ctx context.Context, src, dest string, pull bool) error {
+	if pull {
+		return c.download(ctx, src, dest)
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	if fi.IsDir() {
+		return c.uploadArchive(ctx, src, dest)
+	}
+	return c.upload(ctx, src, dest)
+}
+
+// negotiateCompression pings the server and falls back to
+// COMPRESSION_NONE, logging a warning, if the requested codec isn't among
+// the ones it advertises as supported, so an older server isn't sent a
+// codec it can't decode.
+func (c *Client) negotiateCompression(ctx context.Context) (pb.Compression, error) {
+	if c.opt.Compression == pb.Compression_COMPRESSION_NONE {
+		return pb.Compression_COMPRESSION_NONE, nil
+	}
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return pb.Compression_COMPRESSION_NONE, err
+	}
+	resp, err := cl.Ping(ctx, &pb.PingRequest{Message: "negotiate"})
+	if err != nil {
+		return pb.Compression_COMPRESSION_NONE, fmt.Errorf("failed to ping server: %w", err)
+	}
+	for _, supported := range resp.SupportedCompressions {
+		if supported == c.opt.Compression {
+			return c.opt.Compression, nil
+		}
+	}
+	slog.Warn("server does not support requested compression, falling back to none", "requested", c.opt.Compression)
+	return pb.Compression_COMPRESSION_NONE, nil
+}
+
+func (c *Client) upload(ctx context.Context, src, dest string) (err error) {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	checksum, err := fileChecksum(f)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s: %w", src, err)
+	}
+	offset, err := c.resumeOffset(ctx, f, dest, st.Size())
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		slog.Info("client resuming upload", "src", src, "dest", dest, "offset", offset)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", src, err)
+	}
+	stream, err := cl.Upload(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start upload: %w", err)
+	}
+	compression, err := c.negotiateCompression(ctx)
+	if err != nil {
+		return err
+	}
+	cc, err := newCodec(compression)
+	if err != nil {
+		return err
+	}
+	slog.Info("client uploading", "src", src, "dest", dest, "bytes", st.Size(), "compression", compression)
+	c.progress.Started(src, st.Size())
+	defer func() { c.progress.Finished(err) }()
+	c.progress.Advance(offset)
+	buf := make([]byte, StreamBufferSize)
+	first := true
+	var pending *pb.FileUploadRequest
+	send := func(req *pb.FileUploadRequest) error {
+		if first {
+			req.Filename = dest
+			req.Size = st.Size()
+			req.Offset = offset
+			req.Compression = compression
+			first = false
+		}
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("failed to send %s: %w", src, err)
+		}
+		return nil
+	}
+	for {
+		n, err := f.Read(buf)
+		if err == io.EOF {
+			if pending == nil {
+				pending = &pb.FileUploadRequest{}
+			}
+			pending.Checksum = checksum
+			if err := send(pending); err != nil {
+				return err
+			}
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		if pending != nil {
+			if err := send(pending); err != nil {
+				return err
+			}
+		}
+		chunk, err := cc.encode(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %w", src, err)
+		}
+		pending = &pb.FileUploadRequest{Content: chunk}
+		c.progress.Advance(int64(n))
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to finish upload: %w", err)
+	}
+	slog.Info("client upload completed", "message", resp.Message)
+	return nil
+}
+
+// resumeOffset probes the server for what it already has at dest and, if
+// any prefix of the local file matches, returns the offset to resume from.
+func (c *Client) resumeOffset(ctx context.Context, f *os.File, dest string, size int64) (int64, error) {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := cl.Probe(ctx, &pb.ProbeRequest{Filename: dest, ExpectedSize: size, WindowSize: probeWindowSize})
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe %s: %w", dest, err)
+	}
+	if resp.Size == 0 || len(resp.WindowHashes) == 0 {
+		return 0, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek %s: %w", f.Name(), err)
+	}
+	offset, err := matchingPrefixLength(f, probeWindowSize, resp.WindowHashes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute resume offset for %s: %w", f.Name(), err)
+	}
+	return offset, nil
+}
+
+// matchingPrefixLength hashes f in the same fixed-size windows Probe used
+// and returns how many bytes of the leading windows match remoteHashes.
+func matchingPrefixLength(f *os.File, windowSize int64, remoteHashes [][]byte) (int64, error) {
+	var offset int64
+	buf := make([]byte, windowSize)
+	for _, remote := range remoteHashes {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			if !bytes.Equal(sum[:], remote) {
+				break
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+func fileChecksum(f *os.File) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// uploadArchive walks src and streams it to the server as an
+// UploadArchive call: a manifest message describing every entry followed
+// by content chunks tagged by the entry's index in that manifest.
+func (c *Client) uploadArchive(ctx context.Context, src, dest string) error {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := walkArchiveEntries(src, dest)
+	if err != nil {
+		return err
+	}
+	stream, err := cl.UploadArchive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start archive upload: %w", err)
+	}
+	compression, err := c.negotiateCompression(ctx)
+	if err != nil {
+		return err
+	}
+	cc, err := newCodec(compression)
+	if err != nil {
+		return err
+	}
+	slog.Info("client uploading directory", "src", src, "dest", dest, "entries", len(entries), "compression", compression)
+	if err := stream.Send(&pb.ArchiveUploadRequest{Manifest: &pb.ArchiveManifest{Root: dest, Entries: entries, Compression: compression}}); err != nil {
+		return fmt.Errorf("failed to send archive manifest: %w", err)
+	}
+	buf := make([]byte, StreamBufferSize)
+	for _, entry := range entries {
+		if entry.IsDir || entry.SymlinkTarget != "" {
+			continue
+		}
+		if err := c.sendArchiveFile(stream, src, entry, buf, cc); err != nil {
+			return err
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to finish archive upload: %w", err)
+	}
+	slog.Info("client archive upload completed", "message", resp.Message)
+	return nil
+}
+
+func (c *Client) sendArchiveFile(stream pb.FileTransferService_UploadArchiveClient, src string, entry *pb.ArchiveEntry, buf []byte, cc codec) (err error) {
+	p := filepath.Join(src, entry.Path)
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", p, err)
+	}
+	defer f.Close()
+	c.progress.Started(entry.Path, entry.Size)
+	defer func() { c.progress.Finished(err) }()
+	for {
+		n, err := f.Read(buf)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		content, err := cc.encode(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %w", p, err)
+		}
+		if err := stream.Send(&pb.ArchiveUploadRequest{EntryIndex: entry.Index, Content: content}); err != nil {
+			return fmt.Errorf("failed to send %s: %w", p, err)
+		}
+		c.progress.Advance(int64(n))
+	}
+}
+
+// download fetches src from the server into dest. It tries a single-file
+// Download first and falls back to downloadArchive if the server reports
+// src is a directory.
+func (c *Client) download(ctx context.Context, src, dest string) error {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	offset, err := c.resumeDownloadOffset(ctx, src, dest)
+	if err != nil {
+		return err
+	}
+	stream, err := cl.Download(ctx, &pb.FileDownloadRequest{Filename: src, Compression: c.opt.Compression, Offset: offset})
+	if err != nil {
+		return fmt.Errorf("failed to start download: %w", err)
+	}
+	first, err := stream.Recv()
+	if status.Code(err) == codes.FailedPrecondition {
+		return c.downloadArchive(ctx, src, dest)
+	} else if err != nil {
+		return fmt.Errorf("failed to download %s: %w", src, err)
+	}
+	if offset > 0 {
+		slog.Info("client resuming download", "src", src, "dest", dest, "offset", offset)
+	}
+	slog.Info("client downloading", "src", src, "dest", dest, "bytes", first.Size, "compression", first.Compression)
+	return c.receiveFile(stream, first, dest, offset)
+}
+
+// resumeDownloadOffset probes the server for src's content windows and, if
+// the local dest file already has a matching prefix, returns how many bytes
+// can be kept instead of re-downloaded.
+func (c *Client) resumeDownloadOffset(ctx context.Context, src, dest string) (int64, error) {
+	df, err := os.Open(dest)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", dest, err)
+	}
+	defer df.Close()
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := cl.Probe(ctx, &pb.ProbeRequest{Filename: src, WindowSize: probeWindowSize})
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe %s: %w", src, err)
+	}
+	if resp.Size == 0 || len(resp.WindowHashes) == 0 {
+		return 0, nil
+	}
+	offset, err := matchingPrefixLength(df, probeWindowSize, resp.WindowHashes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute resume offset for %s: %w", dest, err)
+	}
+	return offset, nil
+}
+
+func (c *Client) receiveFile(stream pb.FileTransferService_DownloadClient, first *pb.FileDownloadResponse, dest string, offset int64) (err error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(dest, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer f.Close()
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek %s: %w", dest, err)
+		}
+	}
+	h := sha256.New()
+	if offset > 0 {
+		if err := hashExistingPrefix(dest, offset, h); err != nil {
+			return err
+		}
+	}
+	cc, err := newCodec(first.Compression)
+	if err != nil {
+		return err
+	}
+	c.progress.Started(dest, first.Size)
+	defer func() { c.progress.Finished(err) }()
+	c.progress.Advance(offset)
+	resp := first
+	totalBytes := offset
+	var expectedChecksum []byte
+	for {
+		if len(resp.Content) > 0 {
+			content, err := cc.decode(resp.Content)
+			if err != nil {
+				return fmt.Errorf("failed to decompress %s: %w", dest, err)
+			}
+			if _, err := f.Write(content); err != nil {
+				return fmt.Errorf("failed to write %s: %w", dest, err)
+			}
+			h.Write(content)
+			totalBytes += int64(len(content))
+			c.progress.Advance(int64(len(content)))
+		}
+		if len(resp.Checksum) > 0 {
+			expectedChecksum = resp.Checksum
+			break
+		}
+		next, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to download %s: %w", dest, err)
+		}
+		resp = next
+	}
+	if err := f.Truncate(totalBytes); err != nil {
+		return fmt.Errorf("failed to truncate %s to %d bytes: %w", dest, totalBytes, err)
+	}
+	if len(expectedChecksum) > 0 {
+		if sum := h.Sum(nil); !bytes.Equal(sum, expectedChecksum) {
+			name := f.Name()
+			f.Close()
+			os.Remove(name)
+			return fmt.Errorf("checksum mismatch for %s, removed partial file", name)
+		}
+	}
+	slog.Info("client download completed", "dest", dest, "bytes", totalBytes)
+	return nil
+}
+
+// downloadArchive fetches the directory tree rooted at src from the server
+// into dest, the inverse of uploadArchive.
+func (c *Client) downloadArchive(ctx context.Context, src, dest string) error {
+	cl, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	stream, err := cl.DownloadArchive(ctx, &pb.ArchiveDownloadRequest{Path: src, Compression: c.opt.Compression})
+	if err != nil {
+		return fmt.Errorf("failed to start archive download: %w", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to receive archive manifest: %w", err)
+	}
+	manifest := first.Manifest
+	if manifest == nil {
+		return fmt.Errorf("first message of archive download must carry the manifest")
+	}
+	slog.Info("client downloading directory", "src", src, "dest", dest, "entries", len(manifest.Entries), "compression", manifest.Compression)
+	cc, err := newCodec(manifest.Compression)
+	if err != nil {
+		return err
+	}
+	if err := prepareArchiveEntries(dest, manifest); err != nil {
+		return err
+	}
+	files := map[int64]*os.File{}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	var current int64 = -1
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to receive archive entry: %w", err)
+		}
+		idx := req.EntryIndex
+		if idx < 0 || int(idx) >= len(manifest.Entries) {
+			return fmt.Errorf("entry index %d out of range", idx)
+		}
+		entry := manifest.Entries[idx]
+		if entry.IsDir || entry.SymlinkTarget != "" {
+			continue
+		}
+		if idx != current {
+			if current >= 0 {
+				c.progress.Finished(nil)
+			}
+			c.progress.Started(entry.Path, entry.Size)
+			current = idx
+		}
+		path, err := safeArchivePath(dest, entry.Path)
+		if err != nil {
+			return err
+		}
+		f, ok := files[idx]
+		if !ok {
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(entry.Mode))
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			files[idx] = f
+		}
+		content, err := cc.decode(req.Content)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		if _, err := f.Write(content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		c.progress.Advance(int64(len(content)))
+	}
+	if current >= 0 {
+		c.progress.Finished(nil)
+	}
+	for idx, f := range files {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close %s: %w", filepath.Join(dest, manifest.Entries[idx].Path), err)
+		}
+	}
+	if err := applyArchiveMtimes(dest, manifest); err != nil {
+		return err
+	}
+	slog.Info("client archive download completed", "entries", len(manifest.Entries))
+	return nil
+}
+
+// walkArchiveEntries walks root and builds the manifest entries the server
+// needs to reconstruct the tree, with every entry's path relative to root
+// so the server can join it against the destination directory it was given.
+func walkArchiveEntries(root, dest string) ([]*pb.ArchiveEntry, error) {
+	var entries []*pb.ArchiveEntry
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entry := &pb.ArchiveEntry{
+			Index: int64(len(entries)),
+			Path:  rel,
+			Mode:  uint32(info.Mode().Perm()),
+			Mtime: info.ModTime().Unix(),
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", p, err)
+			}
+			entry.SymlinkTarget = target
+		case info.IsDir():
+			entry.IsDir = true
+		default:
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package grpcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func windowHashesOf(t *testing.T, data []byte, windowSize int64) [][]byte {
+	t.Helper()
+	hashes, err := windowHashes(bytes.NewReader(data), windowSize)
+	if err != nil {
+		t.Fatalf("windowHashes: %v", err)
+	}
+	return hashes
+}
+
+func openTemp(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open temp file: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestMatchingPrefixLength(t *testing.T) {
+	const windowSize = 4
+
+	full := bytes.Repeat([]byte("a"), windowSize*3) // three full windows
+
+	t.Run("identical content matches every window", func(t *testing.T) {
+		remote := windowHashesOf(t, full, windowSize)
+		f := openTemp(t, full)
+		offset, err := matchingPrefixLength(f, windowSize, remote)
+		if err != nil {
+			t.Fatalf("matchingPrefixLength: %v", err)
+		}
+		if offset != int64(len(full)) {
+			t.Errorf("offset = %d, want %d", offset, len(full))
+		}
+	})
+
+	t.Run("divergent window stops the match", func(t *testing.T) {
+		remote := windowHashesOf(t, full, windowSize)
+		local := bytes.Clone(full)
+		local[windowSize+1] = 'b' // corrupt the second window only
+		f := openTemp(t, local)
+		offset, err := matchingPrefixLength(f, windowSize, remote)
+		if err != nil {
+			t.Fatalf("matchingPrefixLength: %v", err)
+		}
+		if offset != windowSize {
+			t.Errorf("offset = %d, want %d (only the first window should match)", offset, windowSize)
+		}
+	})
+
+	t.Run("local file shorter than remote hashes", func(t *testing.T) {
+		remote := windowHashesOf(t, full, windowSize)
+		short := full[:windowSize] // only the first window is present locally
+		f := openTemp(t, short)
+		offset, err := matchingPrefixLength(f, windowSize, remote)
+		if err != nil {
+			t.Fatalf("matchingPrefixLength: %v", err)
+		}
+		if offset != windowSize {
+			t.Errorf("offset = %d, want %d", offset, windowSize)
+		}
+	})
+
+	t.Run("no remote hashes means no resume", func(t *testing.T) {
+		f := openTemp(t, full)
+		offset, err := matchingPrefixLength(f, windowSize, nil)
+		if err != nil {
+			t.Fatalf("matchingPrefixLength: %v", err)
+		}
+		if offset != 0 {
+			t.Errorf("offset = %d, want 0", offset)
+		}
+	})
+}
+
+func TestWindowHashes(t *testing.T) {
+	const windowSize = 4
+	data := bytes.Repeat([]byte("x"), windowSize*2+1) // two full windows plus a partial one
+
+	hashes, err := windowHashes(bytes.NewReader(data), windowSize)
+	if err != nil {
+		t.Fatalf("windowHashes: %v", err)
+	}
+	if len(hashes) != 3 {
+		t.Fatalf("got %d windows, want 3", len(hashes))
+	}
+	last := sha256.Sum256(data[windowSize*2:])
+	if !bytes.Equal(hashes[2], last[:]) {
+		t.Errorf("hash of trailing partial window is wrong")
+	}
+}
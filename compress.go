@@ -0,0 +1,155 @@
+package grpcp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+
+	pb "github.com/fujiwara/grpcp/proto"
+)
+
+// codec compresses and decompresses a single chunk's content payload. It
+// never sees the framing gRPC message, so StreamBufferSize still bounds how
+// much of a file is buffered at once, and the receiver can decode each
+// chunk as it arrives instead of waiting for the whole stream.
+type codec interface {
+	encode(p []byte) ([]byte, error)
+	decode(p []byte) ([]byte, error)
+}
+
+type noneCodec struct{}
+
+// encode copies p because callers may reuse its backing array (e.g. a
+// shared read buffer) before the returned chunk is sent.
+func (noneCodec) encode(p []byte) ([]byte, error) { return append([]byte(nil), p...), nil }
+func (noneCodec) decode(p []byte) ([]byte, error) { return p, nil }
+
+type gzipCodec struct{}
+
+func (gzipCodec) encode(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(p); err != nil {
+		return nil, fmt.Errorf("failed to gzip-compress chunk: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to gzip-compress chunk: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) decode(p []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, fmt.Errorf("failed to gzip-decompress chunk: %w", err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gzip-decompress chunk: %w", err)
+	}
+	return out, nil
+}
+
+// zstdCodec reuses a single encoder/decoder across chunks, which is the
+// usage pattern klauspost/compress recommends for streaming workloads.
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func newZstdCodec() (*zstdCodec, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	return &zstdCodec{enc: enc, dec: dec}, nil
+}
+
+func (c *zstdCodec) encode(p []byte) ([]byte, error) {
+	return c.enc.EncodeAll(p, nil), nil
+}
+
+func (c *zstdCodec) decode(p []byte) ([]byte, error) {
+	out, err := c.dec.DecodeAll(p, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to zstd-decompress chunk: %w", err)
+	}
+	return out, nil
+}
+
+type s2Codec struct{}
+
+func (s2Codec) encode(p []byte) ([]byte, error) {
+	return s2.Encode(nil, p), nil
+}
+
+func (s2Codec) decode(p []byte) ([]byte, error) {
+	out, err := s2.Decode(nil, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to s2-decompress chunk: %w", err)
+	}
+	return out, nil
+}
+
+// newCodec returns the codec for c.
+func newCodec(c pb.Compression) (codec, error) {
+	switch c {
+	case pb.Compression_COMPRESSION_NONE:
+		return noneCodec{}, nil
+	case pb.Compression_COMPRESSION_GZIP:
+		return gzipCodec{}, nil
+	case pb.Compression_COMPRESSION_ZSTD:
+		return newZstdCodec()
+	case pb.Compression_COMPRESSION_S2:
+		return s2Codec{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+}
+
+// newCodecWithFallback is like newCodec but falls back to COMPRESSION_NONE,
+// logging a warning, instead of erroring on a codec it doesn't recognize
+// (e.g. a value a newer client sent that this server predates). It returns
+// the compression actually selected so the caller can report it back.
+func newCodecWithFallback(c pb.Compression) (codec, pb.Compression) {
+	cc, err := newCodec(c)
+	if err != nil {
+		slog.Warn("falling back to no compression", "requested", c, "error", err)
+		return noneCodec{}, pb.Compression_COMPRESSION_NONE
+	}
+	return cc, c
+}
+
+// supportedCompressions is advertised to clients on Ping.
+var supportedCompressions = []pb.Compression{
+	pb.Compression_COMPRESSION_NONE,
+	pb.Compression_COMPRESSION_GZIP,
+	pb.Compression_COMPRESSION_ZSTD,
+	pb.Compression_COMPRESSION_S2,
+}
+
+// parseCompression maps a --compress flag value to its wire enum.
+func parseCompression(s string) (pb.Compression, error) {
+	switch s {
+	case "", "none":
+		return pb.Compression_COMPRESSION_NONE, nil
+	case "gzip":
+		return pb.Compression_COMPRESSION_GZIP, nil
+	case "zstd":
+		return pb.Compression_COMPRESSION_ZSTD, nil
+	case "s2":
+		return pb.Compression_COMPRESSION_S2, nil
+	default:
+		return pb.Compression_COMPRESSION_NONE, fmt.Errorf("unknown compression %q, want one of none, gzip, zstd, s2", s)
+	}
+}
@@ -0,0 +1,85 @@
+package grpcp
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func knownHostsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config dir: %w", err)
+	}
+	return filepath.Join(dir, "grpcp", "known_hosts"), nil
+}
+
+func readKnownHosts(path string) (map[string]string, error) {
+	known := map[string]string{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return known, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts file %s: %w", path, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		known[fields[0]] = fields[1]
+	}
+	return known, nil
+}
+
+func trustKnownHost(path, host, fingerprint string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known hosts file %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s %s\n", host, fingerprint); err != nil {
+		return fmt.Errorf("failed to write known hosts file %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyFingerprint implements SSH-style trust-on-first-use for the
+// self-signed certificates newListener generates: the fingerprint of the
+// first certificate seen for a host is pinned to known_hosts, and later
+// connections must match it unless the caller passes trust to accept a
+// changed fingerprint.
+func verifyFingerprint(host string, certs []*x509.Certificate, trust bool) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no server certificate presented")
+	}
+	sum := sha256.Sum256(certs[0].Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	path, err := knownHostsPath()
+	if err != nil {
+		return err
+	}
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return err
+	}
+	if existing, ok := known[host]; ok {
+		if existing == fingerprint {
+			return nil
+		}
+		if !trust {
+			return fmt.Errorf("certificate fingerprint for %s changed (expected %s, got %s); re-run with --trust to accept it", host, existing, fingerprint)
+		}
+	}
+	slog.Info("trusting certificate fingerprint", "host", host, "fingerprint", fingerprint)
+	return trustKnownHost(path, host, fingerprint)
+}
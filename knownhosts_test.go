@@ -0,0 +1,73 @@
+package grpcp
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+)
+
+func selfSignedCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	cfg, err := genSelfSignedTLS()
+	if err != nil {
+		t.Fatalf("genSelfSignedTLS: %v", err)
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestReadTrustKnownHostsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+
+	known, err := readKnownHosts(path)
+	if err != nil {
+		t.Fatalf("readKnownHosts on missing file: %v", err)
+	}
+	if len(known) != 0 {
+		t.Fatalf("expected no entries for a missing file, got %v", known)
+	}
+
+	if err := trustKnownHost(path, "example:8022", "deadbeef"); err != nil {
+		t.Fatalf("trustKnownHost: %v", err)
+	}
+	if err := trustKnownHost(path, "other:8022", "cafef00d"); err != nil {
+		t.Fatalf("trustKnownHost: %v", err)
+	}
+
+	known, err = readKnownHosts(path)
+	if err != nil {
+		t.Fatalf("readKnownHosts: %v", err)
+	}
+	if known["example:8022"] != "deadbeef" || known["other:8022"] != "cafef00d" {
+		t.Errorf("unexpected known hosts contents: %v", known)
+	}
+}
+
+func TestVerifyFingerprint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	certA := selfSignedCert(t)
+	certB := selfSignedCert(t)
+
+	if err := verifyFingerprint("host:1", []*x509.Certificate{certA}, false); err != nil {
+		t.Fatalf("first connection should trust-on-first-use: %v", err)
+	}
+	if err := verifyFingerprint("host:1", []*x509.Certificate{certA}, false); err != nil {
+		t.Fatalf("matching fingerprint should verify: %v", err)
+	}
+	if err := verifyFingerprint("host:1", []*x509.Certificate{certB}, false); err == nil {
+		t.Fatalf("changed fingerprint without --trust should be rejected")
+	}
+	if err := verifyFingerprint("host:1", []*x509.Certificate{certB}, true); err != nil {
+		t.Fatalf("changed fingerprint with --trust should be accepted: %v", err)
+	}
+	if err := verifyFingerprint("host:1", []*x509.Certificate{certB}, false); err != nil {
+		t.Fatalf("newly trusted fingerprint should verify: %v", err)
+	}
+	if err := verifyFingerprint("host:1", nil, false); err == nil {
+		t.Fatalf("no presented certificate should be rejected")
+	}
+}
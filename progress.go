@@ -0,0 +1,138 @@
+package grpcp
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ProgressReporter receives progress events for a single file transfer.
+// Started begins (or, for the next file in an archive transfer, restarts)
+// tracking a transfer of total bytes; Advance reports n more bytes moved;
+// Finished marks the transfer done, successfully if err is nil.
+type ProgressReporter interface {
+	Started(name string, total int64)
+	Advance(n int64)
+	Finished(err error)
+}
+
+// NewProgressReporter returns a silent reporter when quiet is set or
+// stderr isn't a terminal, and a terminal progress bar otherwise.
+func NewProgressReporter(quiet bool) ProgressReporter {
+	if quiet || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return noopProgress{}
+	}
+	return &ttyProgress{}
+}
+
+type noopProgress struct{}
+
+func (noopProgress) Started(string, int64) {}
+func (noopProgress) Advance(int64)         {}
+func (noopProgress) Finished(error)        {}
+
+// ttyProgress renders a single-line progress bar with transfer rate and
+// ETA to stderr, rewriting the line in place with a carriage return.
+type ttyProgress struct {
+	mu      sync.Mutex
+	name    string
+	total   int64
+	done    int64
+	started time.Time
+}
+
+func (p *ttyProgress) Started(name string, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.name, p.total, p.done, p.started = name, total, 0, time.Now()
+	p.render()
+}
+
+func (p *ttyProgress) Advance(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.render()
+}
+
+func (p *ttyProgress) Finished(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.render()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, " failed: %s\n", err)
+	} else {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// render must be called with p.mu held.
+func (p *ttyProgress) render() {
+	elapsed := time.Since(p.started).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.done) / elapsed
+	}
+	eta := "-"
+	if rate > 0 && p.total > p.done {
+		eta = time.Duration(float64(p.total-p.done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	fmt.Fprintf(os.Stderr, "\r%-24s %s %s/s eta %s", truncateName(p.name, 24), progressBar(p.done, p.total, 30), humanBytes(int64(rate)), eta)
+}
+
+func progressBar(done, total int64, width int) string {
+	if total <= 0 {
+		return "[" + strings.Repeat("?", width) + "]"
+	}
+	filled := int(float64(width) * float64(done) / float64(total))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func truncateName(name string, width int) string {
+	if len(name) <= width {
+		return name
+	}
+	return "…" + name[len(name)-(width-1):]
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// serverProgressLogger emits periodic slog progress lines for a transfer,
+// gated by interval so large files don't spam the log.
+type serverProgressLogger struct {
+	name     string
+	total    int64
+	interval time.Duration
+	last     time.Time
+}
+
+func newServerProgressLogger(name string, total int64) *serverProgressLogger {
+	return &serverProgressLogger{name: name, total: total, interval: 2 * time.Second, last: time.Now()}
+}
+
+func (l *serverProgressLogger) advance(done int64) {
+	if time.Since(l.last) < l.interval {
+		return
+	}
+	l.last = time.Now()
+	slog.Info("transfer progress", "name", l.name, "bytes", done, "total", l.total)
+}
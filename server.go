@@ -1,22 +1,51 @@
 package grpcp
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"hash"
 	"io"
 	"log/slog"
 	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	pb "github.com/fujiwara/grpcp/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type server struct {
 	pb.UnimplementedFileTransferServiceServer
+	signingKey ed25519.PrivateKey
+}
+
+type ServerOption struct {
+	Target *Target
+	TLS    bool
+
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile, when set, makes newListener verify client certificates
+	// against this CA. RequireClientCert rejects the handshake outright
+	// when the client presents none; otherwise it is merely verified if given.
+	ClientCAFile      string
+	RequireClientCert bool
+
+	// SigningKeyFile is an Ed25519 private key (PKCS8, PEM-encoded) used to
+	// sign Verify responses.
+	SigningKeyFile string
 }
 
 var (
@@ -25,7 +54,7 @@ var (
 
 func (s *server) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
 	slog.Info("ping", "message", req.Message)
-	return &pb.PingResponse{Message: "pong"}, nil
+	return &pb.PingResponse{Message: "pong", SupportedCompressions: supportedCompressions}, nil
 }
 
 func newUploadResponse(msg string) *pb.FileUploadResponse {
@@ -43,30 +72,137 @@ func (s *server) Upload(stream pb.FileTransferService_UploadServer) error {
 func (s *server) upload(stream pb.FileTransferService_UploadServer) error {
 	var once sync.Once
 	var f *os.File
+	var cc codec
+	var progress *serverProgressLogger
 	var totalBytes, expectedSize int64
+	var expectedChecksum []byte
+	h := sha256.New()
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			slog.Info("server upload completed", "bytes", totalBytes)
 			if totalBytes != expectedSize {
 				return fmt.Errorf("file size mismatch: expected %d bytes, got %d bytes", expectedSize, totalBytes)
 			}
+			if len(expectedChecksum) > 0 {
+				if sum := h.Sum(nil); !bytes.Equal(sum, expectedChecksum) {
+					name := f.Name()
+					f.Close()
+					os.Remove(name)
+					return fmt.Errorf("checksum mismatch for %s, removed partial file", name)
+				}
+			}
+			// A resumed upload that turns out shorter than what was already
+			// on disk (or a fresh upload to a path with a longer leftover
+			// file) must not leave the old trailing bytes in place.
+			if err := f.Truncate(totalBytes); err != nil {
+				return fmt.Errorf("failed to truncate %s to %d bytes: %w", f.Name(), totalBytes, err)
+			}
+			slog.Info("server upload completed", "bytes", totalBytes)
 			return stream.SendAndClose(newUploadResponse("Upload received successfully"))
 		} else if err != nil {
 			return fmt.Errorf("failed to receive file: %w", err)
 		}
 		once.Do(func() {
-			slog.Info("server accepting upload request", "filename", req.Filename, "bytes", req.Size)
-			f, err = os.OpenFile(req.Filename, os.O_WRONLY|os.O_CREATE, 0644)
+			slog.Info("server accepting upload request", "filename", req.Filename, "bytes", req.Size, "offset", req.Offset, "compression", req.Compression)
 			expectedSize = req.Size
+			progress = newServerProgressLogger(req.Filename, req.Size)
+			if cc, err = newCodec(req.Compression); err != nil {
+				return
+			}
+			f, err = os.OpenFile(req.Filename, os.O_WRONLY|os.O_CREATE, 0644)
+			if err != nil || req.Offset == 0 {
+				return
+			}
+			if _, err = f.Seek(req.Offset, io.SeekStart); err != nil {
+				return
+			}
+			if err = hashExistingPrefix(req.Filename, req.Offset, h); err != nil {
+				return
+			}
+			totalBytes = req.Offset
 		})
 		if err != nil || f == nil {
 			return fmt.Errorf("failed to open file: %w", err)
 		}
-		if n, err := f.Write(req.Content); err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
-		} else {
+		if len(req.Content) > 0 {
+			content, err := cc.decode(req.Content)
+			if err != nil {
+				return fmt.Errorf("failed to decompress chunk: %w", err)
+			}
+			n, err := f.Write(content)
+			if err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			h.Write(content[:n])
 			totalBytes += int64(n)
+			progress.advance(totalBytes)
+		}
+		if len(req.Checksum) > 0 {
+			expectedChecksum = req.Checksum
+		}
+	}
+}
+
+// hashExistingPrefix feeds the first offset bytes already on disk into h so
+// that, combined with the bytes received over the stream, h ends up holding
+// the checksum of the whole file rather than just the resumed tail.
+func hashExistingPrefix(filename string, offset int64, h hash.Hash) error {
+	rf, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for resume hashing: %w", filename, err)
+	}
+	defer rf.Close()
+	if _, err := io.CopyN(h, rf, offset); err != nil {
+		return fmt.Errorf("failed to hash existing prefix of %s: %w", filename, err)
+	}
+	return nil
+}
+
+const defaultProbeWindowSize = 4 * 1024 * 1024
+
+func (s *server) Probe(ctx context.Context, req *pb.ProbeRequest) (*pb.ProbeResponse, error) {
+	f, err := os.Open(req.Filename)
+	if os.IsNotExist(err) {
+		return &pb.ProbeResponse{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", req.Filename, err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", req.Filename, err)
+	}
+	if st.IsDir() {
+		// Nothing to resume; Download reports the directory to the client.
+		return &pb.ProbeResponse{}, nil
+	}
+	windowSize := req.WindowSize
+	if windowSize <= 0 {
+		windowSize = defaultProbeWindowSize
+	}
+	hashes, err := windowHashes(f, windowSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", req.Filename, err)
+	}
+	return &pb.ProbeResponse{Size: st.Size(), WindowHashes: hashes}, nil
+}
+
+// windowHashes splits r into fixed-size windows and returns a SHA-256 hash
+// per window, so the caller can compare against a remote file hashed the
+// same way and find the last window both copies agree on.
+func windowHashes(r io.Reader, windowSize int64) ([][]byte, error) {
+	var hashes [][]byte
+	buf := make([]byte, windowSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			hashes = append(hashes, sum[:])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return hashes, nil
+		} else if err != nil {
+			return nil, err
 		}
 	}
 }
@@ -80,7 +216,8 @@ func (s *server) Download(req *pb.FileDownloadRequest, stream pb.FileTransferSer
 }
 
 func (s *server) download(req *pb.FileDownloadRequest, stream pb.FileTransferService_DownloadServer) error {
-	slog.Info("server accepting download request", "filename", req.Filename)
+	slog.Info("server accepting download request", "filename", req.Filename, "offset", req.Offset, "compression", req.Compression)
+	cc, compression := newCodecWithFallback(req.Compression)
 	f, err := os.Open(req.Filename)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
@@ -90,8 +227,22 @@ func (s *server) download(req *pb.FileDownloadRequest, stream pb.FileTransferSer
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
+	if st.IsDir() {
+		return status.Errorf(codes.FailedPrecondition, "%s is a directory, use DownloadArchive", req.Filename)
+	}
+	checksum, err := fileChecksum(f)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s: %w", req.Filename, err)
+	}
+	if req.Offset > 0 {
+		if _, err := f.Seek(req.Offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek %s: %w", req.Filename, err)
+		}
+	}
 	expectedBytes := st.Size()
-	totalBytes := int64(0)
+	totalBytes := req.Offset
+	progress := newServerProgressLogger(req.Filename, expectedBytes)
+	progress.advance(totalBytes)
 	buf := make([]byte, StreamBufferSize)
 	for {
 		n, err := f.Read(buf)
@@ -100,18 +251,290 @@ func (s *server) download(req *pb.FileDownloadRequest, stream pb.FileTransferSer
 			if totalBytes != expectedBytes {
 				return fmt.Errorf("file size mismatch: expected %d bytes, got %d bytes", expectedBytes, totalBytes)
 			}
-			return nil
+			return stream.Send(&pb.FileDownloadResponse{Filename: req.Filename, Checksum: checksum})
 		} else if err != nil {
 			return fmt.Errorf("failed to read file: %w", err)
 		}
+		content, err := cc.encode(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to compress chunk: %w", err)
+		}
 		if err := stream.Send(&pb.FileDownloadResponse{
-			Filename: req.Filename,
-			Content:  buf[:n],
-			Size:     expectedBytes,
+			Filename:    req.Filename,
+			Content:     content,
+			Size:        expectedBytes,
+			Compression: compression,
 		}); err != nil {
 			return fmt.Errorf("failed to send file: %w", err)
 		}
 		totalBytes += int64(n)
+		progress.advance(totalBytes)
+	}
+}
+
+func (s *server) UploadArchive(stream pb.FileTransferService_UploadArchiveServer) error {
+	if err := s.uploadArchive(stream); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *server) uploadArchive(stream pb.FileTransferService_UploadArchiveServer) error {
+	var manifest *pb.ArchiveManifest
+	var cc codec
+	var progress *serverProgressLogger
+	var totalBytes int64
+	files := map[int64]*os.File{}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to receive archive entry: %w", err)
+		}
+		if manifest == nil {
+			if req.Manifest == nil {
+				return fmt.Errorf("first message of archive upload must carry the manifest")
+			}
+			manifest = req.Manifest
+			slog.Info("server accepting archive upload", "root", manifest.Root, "entries", len(manifest.Entries), "compression", manifest.Compression)
+			if cc, err = newCodec(manifest.Compression); err != nil {
+				return err
+			}
+			progress = newServerProgressLogger(manifest.Root, archiveTotalSize(manifest))
+			if err := prepareArchiveEntries(manifest.Root, manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		idx := req.EntryIndex
+		if idx < 0 || int(idx) >= len(manifest.Entries) {
+			return fmt.Errorf("entry index %d out of range", idx)
+		}
+		entry := manifest.Entries[idx]
+		path, err := safeArchivePath(manifest.Root, entry.Path)
+		if err != nil {
+			return err
+		}
+		f, ok := files[idx]
+		if !ok {
+			// Archive upload has no resume support, unlike the single-file
+			// Upload path, so a re-uploaded file that shrunk must not keep
+			// stale trailing bytes from whatever was there before.
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(entry.Mode))
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			files[idx] = f
+		}
+		content, err := cc.decode(req.Content)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		if _, err := f.Write(content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		totalBytes += int64(len(content))
+		progress.advance(totalBytes)
+	}
+	for idx, f := range files {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close %s: %w", filepath.Join(manifest.Root, manifest.Entries[idx].Path), err)
+		}
+	}
+	if err := applyArchiveMtimes(manifest.Root, manifest); err != nil {
+		return err
+	}
+	slog.Info("server archive upload completed", "entries", len(manifest.GetEntries()))
+	return stream.SendAndClose(&pb.ArchiveUploadResponse{Message: "Archive received successfully"})
+}
+
+// safeArchivePath joins root with entry's manifest-supplied relative path
+// and rejects the result if it would escape root, refusing manifest entries
+// such as "../../etc/cron.d/x" from writing outside the destination tree.
+func safeArchivePath(root, entry string) (string, error) {
+	path := filepath.Join(root, entry)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", fmt.Errorf("invalid archive entry path %q: %w", entry, err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry path %q escapes destination root %q", entry, root)
+	}
+	return path, nil
+}
+
+// applyArchiveMtimes restores each non-symlink entry's recorded modification
+// time. It must run after every entry has been created: creating a file or
+// directory touches its parent directory's mtime, which would otherwise
+// clobber a mtime already set on that parent earlier in the walk.
+func applyArchiveMtimes(root string, manifest *pb.ArchiveManifest) error {
+	for _, entry := range manifest.Entries {
+		if entry.SymlinkTarget != "" {
+			continue
+		}
+		path, err := safeArchivePath(root, entry.Path)
+		if err != nil {
+			return err
+		}
+		mtime := time.Unix(entry.Mtime, 0)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			return fmt.Errorf("failed to set mtime on %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// archiveTotalSize sums the size of every regular-file entry in manifest,
+// for progress reporting against the whole archive transfer.
+func archiveTotalSize(manifest *pb.ArchiveManifest) int64 {
+	var total int64
+	for _, entry := range manifest.Entries {
+		if !entry.IsDir && entry.SymlinkTarget == "" {
+			total += entry.Size
+		}
+	}
+	return total
+}
+
+// prepareArchiveEntries creates directories and symlinks under root up
+// front so that file entries, which may arrive in any order, always have a
+// parent directory to be written into. root is manifest.Root for
+// UploadArchive, and the local destination directory for DownloadArchive.
+func prepareArchiveEntries(root string, manifest *pb.ArchiveManifest) error {
+	for _, entry := range manifest.Entries {
+		path, err := safeArchivePath(root, entry.Path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir:
+			if err := os.MkdirAll(path, os.FileMode(entry.Mode)); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", path, err)
+			}
+		case entry.SymlinkTarget != "":
+			os.Remove(path)
+			if err := os.Symlink(entry.SymlinkTarget, path); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", path, err)
+			}
+		default:
+			if dir := filepath.Dir(path); dir != "." {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return fmt.Errorf("failed to create directory %s: %w", dir, err)
+				}
+			}
+			// Create (or truncate a leftover from a previous transfer of)
+			// every regular file up front, not just when its first content
+			// chunk arrives: an empty file never gets a content chunk at
+			// all, so a lazily-created file would otherwise be silently
+			// missing from the reconstructed tree.
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(entry.Mode))
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", path, err)
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to create %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *server) DownloadArchive(req *pb.ArchiveDownloadRequest, stream pb.FileTransferService_DownloadArchiveServer) error {
+	if err := s.downloadArchive(req, stream); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *server) downloadArchive(req *pb.ArchiveDownloadRequest, stream pb.FileTransferService_DownloadArchiveServer) error {
+	slog.Info("server accepting archive download request", "path", req.Path, "compression", req.Compression)
+	cc, compression := newCodecWithFallback(req.Compression)
+	var entries []*pb.ArchiveEntry
+	err := filepath.Walk(req.Path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(req.Path, p)
+		if err != nil {
+			return err
+		}
+		entry := &pb.ArchiveEntry{
+			Index: int64(len(entries)),
+			Path:  rel,
+			Mode:  uint32(info.Mode().Perm()),
+			Mtime: info.ModTime().Unix(),
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", p, err)
+			}
+			entry.SymlinkTarget = target
+		case info.IsDir():
+			entry.IsDir = true
+		default:
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", req.Path, err)
+	}
+	if err := stream.Send(&pb.ArchiveDownloadResponse{Manifest: &pb.ArchiveManifest{Root: req.Path, Entries: entries, Compression: compression}}); err != nil {
+		return fmt.Errorf("failed to send manifest: %w", err)
+	}
+	progress := newServerProgressLogger(req.Path, archiveTotalSize(&pb.ArchiveManifest{Entries: entries}))
+	var totalBytes int64
+	buf := make([]byte, StreamBufferSize)
+	for _, entry := range entries {
+		if entry.IsDir || entry.SymlinkTarget != "" {
+			continue
+		}
+		n, err := sendArchiveEntry(stream, entry.Index, filepath.Join(req.Path, entry.Path), buf, cc)
+		if err != nil {
+			return err
+		}
+		totalBytes += n
+		progress.advance(totalBytes)
+	}
+	slog.Info("server archive download completed", "entries", len(entries))
+	return nil
+}
+
+// sendArchiveEntry streams path's content and returns how many uncompressed
+// bytes were sent, for the caller to track overall archive progress.
+func sendArchiveEntry(stream pb.FileTransferService_DownloadArchiveServer, index int64, path string, buf []byte, cc codec) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	var sent int64
+	for {
+		n, err := f.Read(buf)
+		if err == io.EOF {
+			return sent, nil
+		} else if err != nil {
+			return sent, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		content, err := cc.encode(buf[:n])
+		if err != nil {
+			return sent, fmt.Errorf("failed to compress %s: %w", path, err)
+		}
+		if err := stream.Send(&pb.ArchiveDownloadResponse{EntryIndex: index, Content: content}); err != nil {
+			return sent, fmt.Errorf("failed to send %s: %w", path, err)
+		}
+		sent += int64(n)
 	}
 }
 
@@ -126,8 +549,20 @@ func (s *server) Shutdown(ctx context.Context, req *pb.ShutdownRequest) (*pb.Shu
 	return &pb.ShutdownResponse{}, nil
 }
 
-func newListener(addr string, opt *ServerOption) (net.Listener, error) {
-	lis, err := net.Listen("tcp", addr)
+func newListener(target *Target, opt *ServerOption) (net.Listener, error) {
+	if opt.RequireClientCert && opt.ClientCAFile == "" {
+		return nil, fmt.Errorf("--require-client-cert requires --client-ca-file")
+	}
+	var lis net.Listener
+	var err error
+	switch target.Transport {
+	case "unix":
+		lis, err = net.Listen("unix", target.Addr)
+	case "stdio":
+		lis = newStdioListener()
+	default:
+		lis, err = net.Listen("tcp", target.Addr)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen: %w", err)
 	}
@@ -150,18 +585,80 @@ func newListener(addr string, opt *ServerOption) (net.Listener, error) {
 			return nil, fmt.Errorf("failed to generate tls config: %w", err)
 		}
 	}
+	if opt.ClientCAFile != "" {
+		slog.Info("requiring client certificates", "ca", opt.ClientCAFile, "required", opt.RequireClientCert)
+		pool, err := loadCAPool(opt.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		if opt.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
 	return tls.NewListener(lis, tlsConfig), nil
 }
 
+func (s *server) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
+	if s.signingKey == nil {
+		return nil, fmt.Errorf("server has no signing key configured")
+	}
+	f, err := os.Open(req.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", req.Filename, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to checksum %s: %w", req.Filename, err)
+	}
+	sum := h.Sum(nil)
+	return &pb.VerifyResponse{
+		Checksum:  sum,
+		Signature: ed25519.Sign(s.signingKey, sum),
+		PublicKey: s.signingKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// loadSigningKey reads a PEM-encoded PKCS8 Ed25519 private key, the format
+// `openssl genpkey -algorithm ed25519` produces.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
 func RunServer(ctx context.Context, opt *ServerOption) error {
 	s := grpc.NewServer()
-	addr := fmt.Sprintf("%s:%d", opt.Listen, opt.Port)
-	lis, err := newListener(addr, opt)
+	lis, err := newListener(opt.Target, opt)
 	if err != nil {
 		return fmt.Errorf("failed to create listener: %w", err)
 	}
-	slog.Info("starting server", "addr", addr, "tls", opt.TLS)
-	pb.RegisterFileTransferServiceServer(s, &server{})
+	srv := &server{}
+	if opt.SigningKeyFile != "" {
+		srv.signingKey, err = loadSigningKey(opt.SigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
+	}
+	slog.Info("starting server", "transport", opt.Target.Transport, "addr", opt.Target.Addr, "tls", opt.TLS)
+	pb.RegisterFileTransferServiceServer(s, srv)
 	if err := s.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
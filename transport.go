@@ -0,0 +1,146 @@
+package grpcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Target describes where a grpcp server listens, or a grpcp client dials:
+// over TCP, a Unix domain socket, or the stdio of a tunneled subprocess.
+// The stdio transport lets grpcp ride an existing tunnel (e.g. an ssh
+// session) the way scp/rsync do, with no port opened on either end.
+type Target struct {
+	Transport string // "tcp", "unix", or "stdio"
+	Addr      string // host:port for tcp, socket path for unix, shell command for stdio
+}
+
+// ParseTarget parses a URL-style target ("tcp://host:port", "unix:///path",
+// "stdio://<command>") or a bare "host:port", which is treated as tcp for
+// backward compatibility with --host/--port.
+func ParseTarget(s string) (*Target, error) {
+	switch {
+	case strings.HasPrefix(s, "tcp://"):
+		return &Target{Transport: "tcp", Addr: strings.TrimPrefix(s, "tcp://")}, nil
+	case strings.HasPrefix(s, "unix://"):
+		return &Target{Transport: "unix", Addr: strings.TrimPrefix(s, "unix://")}, nil
+	case strings.HasPrefix(s, "stdio://"):
+		return &Target{Transport: "stdio", Addr: strings.TrimPrefix(s, "stdio://")}, nil
+	case strings.Contains(s, "://"):
+		return nil, fmt.Errorf("unsupported transport in target %q", s)
+	default:
+		return &Target{Transport: "tcp", Addr: s}, nil
+	}
+}
+
+// String renders target back to its URL-style form, used as the known_hosts
+// key for TLS fingerprint pinning and in log messages.
+func (t *Target) String() string {
+	return fmt.Sprintf("%s://%s", t.Transport, t.Addr)
+}
+
+// dialTarget opens a net.Conn to target, for use as a gRPC custom dialer.
+func dialTarget(ctx context.Context, target *Target) (net.Conn, error) {
+	switch target.Transport {
+	case "unix":
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", target.Addr)
+	case "stdio":
+		return dialStdio(ctx, target.Addr)
+	default:
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", target.Addr)
+	}
+}
+
+// dialStdio runs command through the shell and wraps its stdin/stdout as a
+// net.Conn, so the client speaks gRPC through whatever tunnel the command
+// establishes, e.g. "ssh user@host grpcp --server --transport stdio".
+func dialStdio(ctx context.Context, command string) (net.Conn, error) {
+	if command == "" {
+		return nil, fmt.Errorf("stdio transport requires a command to run")
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin pipe for %q: %w", command, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout pipe for %q: %w", command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %q: %w", command, err)
+	}
+	return &stdioConn{Reader: stdout, Writer: stdin, closer: stdin, cmd: cmd}, nil
+}
+
+// stdioConn adapts a pair of byte streams to net.Conn, which is all gRPC's
+// transport needs: no addressing, just Read/Write/Close/deadlines that can
+// be no-ops. closer, if set, is closed by Close before waiting on cmd.
+type stdioConn struct {
+	io.Reader
+	io.Writer
+	closer io.Closer
+	cmd    *exec.Cmd
+}
+
+func (c *stdioConn) Close() error {
+	var err error
+	if c.closer != nil {
+		err = c.closer.Close()
+	}
+	if c.cmd != nil {
+		c.cmd.Wait()
+	}
+	return err
+}
+
+func (c *stdioConn) LocalAddr() net.Addr                { return stdioAddr{} }
+func (c *stdioConn) RemoteAddr() net.Addr               { return stdioAddr{} }
+func (c *stdioConn) SetDeadline(t time.Time) error      { return nil }
+func (c *stdioConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *stdioConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type stdioAddr struct{}
+
+func (stdioAddr) Network() string { return "stdio" }
+func (stdioAddr) String() string  { return "stdio" }
+
+// stdioListener is a net.Listener that hands out exactly one connection,
+// wrapping the process's own stdin/stdout, for a server invoked at the far
+// end of a tunnel (e.g. `ssh user@host grpcp --server --transport stdio`).
+type stdioListener struct {
+	used   bool
+	closed chan struct{}
+}
+
+func newStdioListener() *stdioListener {
+	return &stdioListener{closed: make(chan struct{})}
+}
+
+func (l *stdioListener) Accept() (net.Conn, error) {
+	if l.used {
+		<-l.closed
+		return nil, io.EOF
+	}
+	l.used = true
+	return &stdioConn{Reader: os.Stdin, Writer: os.Stdout}, nil
+}
+
+func (l *stdioListener) Close() error {
+	select {
+	case <-l.closed:
+	default:
+		close(l.closed)
+	}
+	return nil
+}
+
+func (l *stdioListener) Addr() net.Addr { return stdioAddr{} }
@@ -0,0 +1,72 @@
+package grpcp
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	cases := []struct {
+		name          string
+		in            string
+		wantTransport string
+		wantAddr      string
+		wantErr       bool
+	}{
+		{name: "tcp url", in: "tcp://example:8022", wantTransport: "tcp", wantAddr: "example:8022"},
+		{name: "unix url", in: "unix:///var/run/grpcp.sock", wantTransport: "unix", wantAddr: "/var/run/grpcp.sock"},
+		{name: "stdio url", in: "stdio://ssh host grpcp --server --transport stdio", wantTransport: "stdio", wantAddr: "ssh host grpcp --server --transport stdio"},
+		{name: "bare host:port defaults to tcp", in: "example:8022", wantTransport: "tcp", wantAddr: "example:8022"},
+		{name: "unsupported scheme", in: "ftp://example", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := ParseTarget(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTarget(%q) = %v, want error", tc.in, target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTarget(%q): %v", tc.in, err)
+			}
+			if target.Transport != tc.wantTransport || target.Addr != tc.wantAddr {
+				t.Errorf("ParseTarget(%q) = %+v, want {%s %s}", tc.in, target, tc.wantTransport, tc.wantAddr)
+			}
+		})
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	cases := []struct {
+		name          string
+		transport     string
+		host          string
+		port          int
+		wantTransport string
+		wantAddr      string
+		wantErr       bool
+	}{
+		{name: "tcp with explicit host", transport: "tcp", host: "example", port: 8022, wantTransport: "tcp", wantAddr: "example:8022"},
+		{name: "tcp with empty host defaults to localhost", transport: "tcp", host: "", port: 8022, wantTransport: "tcp", wantAddr: "localhost:8022"},
+		{name: "unix with explicit host", transport: "unix", host: "/var/run/grpcp.sock", wantTransport: "unix", wantAddr: "/var/run/grpcp.sock"},
+		{name: "unix with empty host is rejected", transport: "unix", host: "", wantErr: true},
+		{name: "stdio with empty host is rejected", transport: "stdio", host: "", wantErr: true},
+		{name: "url-style host takes priority over transport", transport: "tcp", host: "unix:///var/run/grpcp.sock", wantTransport: "unix", wantAddr: "/var/run/grpcp.sock"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := resolveTarget(tc.transport, tc.host, tc.port)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("resolveTarget(%q, %q, %d) = %v, want error", tc.transport, tc.host, tc.port, target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveTarget(%q, %q, %d): %v", tc.transport, tc.host, tc.port, err)
+			}
+			if target.Transport != tc.wantTransport || target.Addr != tc.wantAddr {
+				t.Errorf("resolveTarget(%q, %q, %d) = %+v, want {%s %s}", tc.transport, tc.host, tc.port, target, tc.wantTransport, tc.wantAddr)
+			}
+		})
+	}
+}